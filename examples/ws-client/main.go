@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "github.com/sumit760/event-driven-services-demo/services/order-service/proto"
+)
+
+const (
+	orderServiceAddress = "localhost:30051" // NodePort service
+	orderServiceWSURL   = "ws://localhost:30081"
+	wsAPIKeyHeader      = "X-API-Key"
+)
+
+func main() {
+	fmt.Println("🚀 Event-Driven Microservices Demo - WebSocket Client")
+	fmt.Println("======================================================")
+
+	customerID := "customer-123"
+
+	fmt.Println("\n🔌 Connecting to order status stream...")
+	wsURL := fmt.Sprintf("%s/ws/orders/%s", orderServiceWSURL, customerID)
+
+	// The stream requires an API key or bearer token; see authMiddleware in
+	// services/order-service/websocket.go.
+	header := http.Header{}
+	if apiKey := os.Getenv("ORDER_SERVICE_API_KEY"); apiKey != "" {
+		header.Set(wsAPIKeyHeader, apiKey)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to websocket: %v", err)
+	}
+	defer conn.Close()
+	fmt.Printf("✅ Connected to %s\n", wsURL)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				log.Printf("Stream closed: %v", err)
+				return
+			}
+			fmt.Printf("📡 Order event: %s\n", message)
+		}
+	}()
+
+	fmt.Println("\n📦 Triggering a new order via gRPC...")
+	if err := createOrder(customerID); err != nil {
+		log.Fatalf("❌ Failed to create order: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		fmt.Println("\n⏱️  Timed out waiting for further status transitions")
+	}
+}
+
+func createOrder(customerID string) error {
+	conn, err := grpc.Dial(orderServiceAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to connect to order service: %w", err)
+	}
+	defer conn.Close()
+
+	client := pb.NewOrderServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resp, err := client.CreateOrder(ctx, &pb.CreateOrderRequest{
+		CustomerId: customerID,
+		Items: []*pb.OrderItem{
+			{
+				ProductId: "product-456",
+				Quantity:  2,
+				UnitPrice: 29.99,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Order created: %s (status: %s)\n", resp.Order.OrderId, resp.Order.Status)
+	return nil
+}
@@ -0,0 +1,76 @@
+// Package cloudevents implements the subset of the CloudEvents 1.0 envelope
+// (https://github.com/cloudevents/spec) used by this repo's Dapr pub/sub
+// events.
+package cloudevents
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContentType is the Dapr HTTP content type that marks a published message
+// as a CloudEvents 1.0 envelope.
+const ContentType = "application/cloudevents+json"
+
+const specVersion = "1.0"
+
+// Event is a CloudEvents 1.0 envelope.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	ID              string          `json:"id"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Subject         string          `json:"subject,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	// TraceParent carries the W3C traceparent as a CloudEvents extension
+	// attribute so subscribers can continue the same distributed trace.
+	TraceParent string `json:"traceparent,omitempty"`
+}
+
+// NewEvent wraps data in a CloudEvents 1.0 envelope. source identifies the
+// producing service (e.g. "https://order-service") and subject is typically
+// the id of the resource the event is about (e.g. an order id).
+func NewEvent(source, eventType, subject string, data interface{}) (*Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cloudevent data: %w", err)
+	}
+
+	return &Event{
+		SpecVersion:     specVersion,
+		Type:            eventType,
+		Source:          source,
+		ID:              uuid.New().String(),
+		Time:            time.Now(),
+		DataContentType: "application/json",
+		Subject:         subject,
+		Data:            payload,
+	}, nil
+}
+
+// Marshal serializes the envelope to JSON.
+func (e *Event) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Unmarshal parses a CloudEvents 1.0 JSON envelope.
+func Unmarshal(data []byte) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cloudevent: %w", err)
+	}
+	return &event, nil
+}
+
+// UnmarshalData decodes the envelope's data payload into v.
+func (e *Event) UnmarshalData(v interface{}) error {
+	if len(e.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(e.Data, v)
+}
@@ -0,0 +1,51 @@
+package logctx
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const traceParentMetadataKey = "traceparent"
+
+// UnaryServerInterceptor extracts (or generates) a W3C traceparent from
+// incoming gRPC metadata and attaches a request-scoped logger to the
+// context before invoking the handler.
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx = withTraceParentFromMetadata(ctx)
+	Info(ctx, "handling grpc request", "method", info.FullMethod)
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor is the streaming counterpart of
+// UnaryServerInterceptor.
+func StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := withTraceParentFromMetadata(ss.Context())
+	Info(ctx, "handling grpc stream", "method", info.FullMethod)
+	return handler(srv, &loggingServerStream{ServerStream: ss, ctx: ctx})
+}
+
+type loggingServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *loggingServerStream) Context() context.Context { return s.ctx }
+
+func withTraceParentFromMetadata(ctx context.Context) context.Context {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(traceParentMetadataKey); len(values) > 0 {
+			return WithTraceParent(ctx, ParseTraceParent(values[0]))
+		}
+	}
+	return WithTraceParent(ctx, NewTraceParent())
+}
+
+// OutgoingGRPCContext attaches ctx's traceparent, advanced to a child span,
+// to outgoing gRPC metadata. Use it before a Dapr service invocation made
+// over gRPC so the callee continues the same trace.
+func OutgoingGRPCContext(ctx context.Context) context.Context {
+	tp := TraceParentFrom(ctx).NewChildSpan()
+	return metadata.AppendToOutgoingContext(ctx, traceParentMetadataKey, tp.String())
+}
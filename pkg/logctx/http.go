@@ -0,0 +1,30 @@
+package logctx
+
+import (
+	"context"
+	"net/http"
+)
+
+// TraceParentHeader is the W3C trace context header name.
+const TraceParentHeader = "traceparent"
+
+// HTTPMiddleware extracts (or generates) a W3C traceparent from the
+// incoming request and attaches a request-scoped logger to its context.
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tp TraceParent
+		if header := r.Header.Get(TraceParentHeader); header != "" {
+			tp = ParseTraceParent(header)
+		} else {
+			tp = NewTraceParent()
+		}
+		next.ServeHTTP(w, r.WithContext(WithTraceParent(r.Context(), tp)))
+	})
+}
+
+// SetOutgoingHeader propagates ctx's traceparent, advanced to a child span,
+// onto an outbound HTTP request.
+func SetOutgoingHeader(ctx context.Context, req *http.Request) {
+	tp := TraceParentFrom(ctx).NewChildSpan()
+	req.Header.Set(TraceParentHeader, tp.String())
+}
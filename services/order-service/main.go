@@ -6,27 +6,42 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"os"
 	"time"
 
 	"github.com/dapr/go-sdk/client"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/sumit760/event-driven-services-demo/pkg/cloudevents"
+	"github.com/sumit760/event-driven-services-demo/pkg/logctx"
 	pb "github.com/sumit760/event-driven-services-demo/services/order-service/proto"
 )
 
 const (
-	port           = ":50051"
-	daprHTTPPort   = "3500"
-	pubsubName     = "kafka-pubsub"
-	stateStoreName = "redis-statestore"
+	port             = ":50051"
+	daprHTTPPort     = "3500"
+	pubsubName       = "kafka-pubsub"
+	stateStoreName   = "redis-statestore"
+	redisAddr        = "redis-master:6379"
+	cloudEventSource = "https://order-service"
 )
 
+// cloudEventType maps an internal topic name (e.g. "order.created") to the
+// reverse-DNS CloudEvents type (e.g. "com.example.order.created").
+func cloudEventType(topic string) string {
+	return "com.example." + topic
+}
+
 // OrderService implements the gRPC OrderService
 type OrderService struct {
 	pb.UnimplementedOrderServiceServer
-	daprClient client.Client
+	daprClient      client.Client
+	redisClient     *redis.Client
+	sagaCoordinator *SagaCoordinator
 }
 
 // OrderEvent represents an order event for Kafka
@@ -37,8 +52,11 @@ type OrderEvent struct {
 	CustomerID  string    `json:"customer_id"`
 	TotalAmount float64   `json:"total_amount"`
 	Status      string    `json:"status"`
-	Timestamp   time.Time `json:"timestamp"`
-	Data        *pb.Order `json:"data"`
+	// Step is the saga step name for saga.step.completed, saga.compensating
+	// and saga.failed events (e.g. "charge-payment"), empty otherwise.
+	Step      string    `json:"step,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      *pb.Order `json:"data"`
 }
 
 // NewOrderService creates a new OrderService instance
@@ -48,19 +66,23 @@ func NewOrderService() *OrderService {
 		log.Fatalf("Failed to create Dapr client: %v", err)
 	}
 
-	return &OrderService{
-		daprClient: daprClient,
+	orderService := &OrderService{
+		daprClient:  daprClient,
+		redisClient: redis.NewClient(&redis.Options{Addr: redisAddr}),
 	}
+	orderService.sagaCoordinator = NewSagaCoordinator(orderService)
+	return orderService
 }
 
 // CreateOrder handles order creation
 func (s *OrderService) CreateOrder(ctx context.Context, req *pb.CreateOrderRequest) (*pb.CreateOrderResponse, error) {
-	log.Printf("Creating order for customer: %s", req.CustomerId)
-
 	// Generate order ID
 	orderID := uuid.New().String()
 	now := time.Now()
 
+	ctx = logctx.WithFields(ctx, "order_id", orderID, "customer_id", req.CustomerId)
+	logctx.Info(ctx, "creating order", "customer_id", req.CustomerId)
+
 	// Calculate total amount
 	var totalAmount float64
 	for _, item := range req.Items {
@@ -85,7 +107,7 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *pb.CreateOrderReque
 	// Save order to state store
 	orderData, err := json.Marshal(order)
 	if err != nil {
-		log.Printf("Failed to marshal order: %v", err)
+		logctx.Error(ctx, "failed to marshal order", "err", err)
 		return &pb.CreateOrderResponse{
 			Success: false,
 			Message: "Failed to create order",
@@ -94,7 +116,7 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *pb.CreateOrderReque
 
 	err = s.daprClient.SaveState(ctx, stateStoreName, orderID, orderData, nil)
 	if err != nil {
-		log.Printf("Failed to save order to state store: %v", err)
+		logctx.Error(ctx, "failed to save order to state store", "err", err)
 		return &pb.CreateOrderResponse{
 			Success: false,
 			Message: "Failed to save order",
@@ -104,7 +126,7 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *pb.CreateOrderReque
 	// Check inventory availability via Dapr service invocation
 	inventoryAvailable, err := s.checkInventoryAvailability(ctx, req.Items)
 	if err != nil {
-		log.Printf("Failed to check inventory: %v", err)
+		logctx.Error(ctx, "failed to check inventory", "err", err)
 		return &pb.CreateOrderResponse{
 			Success: false,
 			Message: "Failed to check inventory availability",
@@ -134,11 +156,18 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *pb.CreateOrderReque
 
 	err = s.publishEvent(ctx, "order.created", event)
 	if err != nil {
-		log.Printf("Failed to publish order created event: %v", err)
+		logctx.Error(ctx, "failed to publish order created event", "err", err)
 		// Don't fail the order creation if event publishing fails
 	}
 
-	log.Printf("Order created successfully: %s", orderID)
+	// Hand fulfillment off to the saga coordinator and return as soon as it
+	// has started; the saga drives reservation, payment, shipment and
+	// notification asynchronously and compensates on failure.
+	if err := s.sagaCoordinator.StartSaga(ctx, order); err != nil {
+		logctx.Error(ctx, "failed to start fulfillment saga", "err", err)
+	}
+
+	logctx.Info(ctx, "order created successfully")
 	return &pb.CreateOrderResponse{
 		Order:   order,
 		Success: true,
@@ -148,12 +177,13 @@ func (s *OrderService) CreateOrder(ctx context.Context, req *pb.CreateOrderReque
 
 // GetOrder retrieves an order by ID
 func (s *OrderService) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.GetOrderResponse, error) {
-	log.Printf("Getting order: %s", req.OrderId)
+	ctx = logctx.WithFields(ctx, "order_id", req.OrderId)
+	logctx.Info(ctx, "getting order")
 
 	// Get order from state store
 	result, err := s.daprClient.GetState(ctx, stateStoreName, req.OrderId, nil)
 	if err != nil {
-		log.Printf("Failed to get order from state store: %v", err)
+		logctx.Error(ctx, "failed to get order from state store", "err", err)
 		return &pb.GetOrderResponse{
 			Success: false,
 			Message: "Failed to retrieve order",
@@ -170,7 +200,7 @@ func (s *OrderService) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*
 	var order pb.Order
 	err = json.Unmarshal(result.Value, &order)
 	if err != nil {
-		log.Printf("Failed to unmarshal order: %v", err)
+		logctx.Error(ctx, "failed to unmarshal order", "err", err)
 		return &pb.GetOrderResponse{
 			Success: false,
 			Message: "Failed to parse order data",
@@ -186,7 +216,8 @@ func (s *OrderService) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*
 
 // UpdateOrder updates an existing order
 func (s *OrderService) UpdateOrder(ctx context.Context, req *pb.UpdateOrderRequest) (*pb.UpdateOrderResponse, error) {
-	log.Printf("Updating order: %s", req.OrderId)
+	ctx = logctx.WithFields(ctx, "order_id", req.OrderId)
+	logctx.Info(ctx, "updating order", "status", req.Status.String())
 
 	// Get existing order
 	getResp, err := s.GetOrder(ctx, &pb.GetOrderRequest{OrderId: req.OrderId})
@@ -234,7 +265,7 @@ func (s *OrderService) UpdateOrder(ctx context.Context, req *pb.UpdateOrderReque
 
 		err = s.publishEvent(ctx, "order.updated", event)
 		if err != nil {
-			log.Printf("Failed to publish order updated event: %v", err)
+			logctx.Error(ctx, "failed to publish order updated event", "err", err)
 		}
 	}
 
@@ -247,7 +278,8 @@ func (s *OrderService) UpdateOrder(ctx context.Context, req *pb.UpdateOrderReque
 
 // CancelOrder cancels an existing order
 func (s *OrderService) CancelOrder(ctx context.Context, req *pb.CancelOrderRequest) (*pb.CancelOrderResponse, error) {
-	log.Printf("Cancelling order: %s", req.OrderId)
+	ctx = logctx.WithFields(ctx, "order_id", req.OrderId)
+	logctx.Info(ctx, "cancelling order", "reason", req.Reason)
 
 	// Update order status to cancelled
 	updateResp, err := s.UpdateOrder(ctx, &pb.UpdateOrderRequest{
@@ -265,17 +297,17 @@ func (s *OrderService) CancelOrder(ctx context.Context, req *pb.CancelOrderReque
 
 	// Publish order cancelled event
 	event := OrderEvent{
-		EventID:    uuid.New().String(),
-		EventType:  "order.cancelled",
-		OrderID:    req.OrderId,
-		Status:     "cancelled",
-		Timestamp:  time.Now(),
-		Data:       updateResp.Order,
+		EventID:   uuid.New().String(),
+		EventType: "order.cancelled",
+		OrderID:   req.OrderId,
+		Status:    "cancelled",
+		Timestamp: time.Now(),
+		Data:      updateResp.Order,
 	}
 
 	err = s.publishEvent(ctx, "order.cancelled", event)
 	if err != nil {
-		log.Printf("Failed to publish order cancelled event: %v", err)
+		logctx.Error(ctx, "failed to publish order cancelled event", "err", err)
 	}
 
 	return &pb.CancelOrderResponse{
@@ -286,7 +318,8 @@ func (s *OrderService) CancelOrder(ctx context.Context, req *pb.CancelOrderReque
 
 // ListOrders lists orders for a customer
 func (s *OrderService) ListOrders(ctx context.Context, req *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
-	log.Printf("Listing orders for customer: %s", req.CustomerId)
+	ctx = logctx.WithFields(ctx, "customer_id", req.CustomerId)
+	logctx.Info(ctx, "listing orders")
 
 	// This is a simplified implementation
 	// In a real system, you'd implement proper pagination and filtering
@@ -297,6 +330,58 @@ func (s *OrderService) ListOrders(ctx context.Context, req *pb.ListOrdersRequest
 	}, nil
 }
 
+// GetOrderSaga returns the current step and history of an order's
+// fulfillment saga.
+func (s *OrderService) GetOrderSaga(ctx context.Context, req *pb.GetOrderSagaRequest) (*pb.GetOrderSagaResponse, error) {
+	ctx = logctx.WithFields(ctx, "order_id", req.OrderId)
+	logctx.Info(ctx, "getting saga")
+
+	state, err := s.sagaCoordinator.GetSaga(ctx, req.OrderId)
+	if err != nil {
+		logctx.Error(ctx, "failed to get saga", "err", err)
+		return &pb.GetOrderSagaResponse{
+			Success: false,
+			Message: "Failed to retrieve saga state",
+		}, err
+	}
+
+	if state == nil {
+		return &pb.GetOrderSagaResponse{
+			Success: false,
+			Message: "Saga not found",
+		}, nil
+	}
+
+	return &pb.GetOrderSagaResponse{
+		Saga:    toProtoSagaState(state),
+		Success: true,
+		Message: "Saga retrieved successfully",
+	}, nil
+}
+
+// toProtoSagaState converts the internal saga representation to its wire
+// form for the GetOrderSaga RPC.
+func toProtoSagaState(state *SagaState) *pb.SagaState {
+	steps := make([]*pb.SagaStepState, 0, len(state.Steps))
+	for _, step := range state.Steps {
+		steps = append(steps, &pb.SagaStepState{
+			Name:      step.Name,
+			Status:    pb.SagaStepStatus(pb.SagaStepStatus_value["SAGA_STEP_STATUS_"+string(step.Status)]),
+			Attempts:  int32(step.Attempts),
+			UpdatedAt: step.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+
+	return &pb.SagaState{
+		OrderId:       state.OrderID,
+		CorrelationId: state.CorrelationID,
+		Status:        pb.SagaStatus(pb.SagaStatus_value["SAGA_STATUS_"+string(state.Status)]),
+		Steps:         steps,
+		CreatedAt:     state.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     state.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
 // checkInventoryAvailability checks if inventory is available for order items
 func (s *OrderService) checkInventoryAvailability(ctx context.Context, items []*pb.OrderItem) (bool, error) {
 	// Call inventory service via Dapr service invocation
@@ -311,9 +396,9 @@ func (s *OrderService) checkInventoryAvailability(ctx context.Context, items []*
 			return false, err
 		}
 
-		resp, err := s.daprClient.InvokeMethod(ctx, "inventory-service", "check-availability", "POST", reqData)
+		resp, err := s.daprClient.InvokeMethod(logctx.OutgoingGRPCContext(ctx), "inventory-service", "check-availability", "POST", reqData)
 		if err != nil {
-			log.Printf("Failed to call inventory service: %v", err)
+			logctx.Warn(ctx, "failed to call inventory service", "err", err)
 			// For demo purposes, assume inventory is available if service is not reachable
 			return true, nil
 		}
@@ -332,38 +417,144 @@ func (s *OrderService) checkInventoryAvailability(ctx context.Context, items []*
 	return true, nil
 }
 
-// publishEvent publishes an event to Kafka via Dapr
+// publishEvent wraps event in a CloudEvents 1.0 envelope and publishes it to
+// Kafka via Dapr.
 func (s *OrderService) publishEvent(ctx context.Context, topic string, event OrderEvent) error {
-	eventData, err := json.Marshal(event)
+	return s.publish(ctx, topic, event.OrderID, event.CustomerID, event.EventID, event)
+}
+
+// publishFillEvent wraps a fill-related event in a CloudEvents 1.0 envelope
+// and publishes it to Kafka via Dapr.
+func (s *OrderService) publishFillEvent(ctx context.Context, topic string, event FillEvent) error {
+	return s.publish(ctx, topic, event.OrderID, "", event.EventID, event)
+}
+
+// publish is the shared path for every event this service emits: it wraps
+// payload in a CloudEvents 1.0 envelope, publishes it to Kafka via Dapr, fans
+// it out to the Redis channels the WebSocket endpoint subscribes to, and
+// delivers it to any matching webhook subscriptions.
+func (s *OrderService) publish(ctx context.Context, topic, orderID, customerID, eventID string, payload interface{}) (err error) {
+	eventData, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	err = s.daprClient.PublishEvent(ctx, pubsubName, topic, eventData)
+	ce, err := cloudevents.NewEvent(cloudEventSource, cloudEventType(topic), orderID, payload)
+	if err != nil {
+		return fmt.Errorf("failed to build cloudevent for topic %s: %w", topic, err)
+	}
+	ce.TraceParent = logctx.TraceParentFrom(ctx).NewChildSpan().String()
+
+	ceData, err := ce.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal cloudevent for topic %s: %w", topic, err)
+	}
+
+	spanCtx, endSpan := logctx.StartSpan(ctx, "dapr.publish "+topic)
+	defer func() { endSpan(&err) }()
+
+	err = s.daprClient.PublishEvent(logctx.OutgoingGRPCContext(spanCtx), pubsubName, topic, ceData, client.PublishEventWithContentType(cloudevents.ContentType))
 	if err != nil {
 		return fmt.Errorf("failed to publish event to topic %s: %w", topic, err)
 	}
 
-	log.Printf("Published event %s to topic %s", event.EventID, topic)
+	s.fanOutToRedis(ctx, orderID, customerID, eventID, eventData)
+	s.deliverToSubscriptions(ctx, topic, ce)
+
+	logctx.Info(ctx, "published event", "event_id", eventID, "topic", topic)
 	return nil
 }
 
+// fanOutToRedis publishes the event to the customer and order channels used
+// by the WebSocket streaming endpoint, and records it for replay by clients
+// that reconnect with a last_event_id.
+func (s *OrderService) fanOutToRedis(ctx context.Context, orderID, customerID, eventID string, eventData []byte) {
+	if customerID != "" {
+		if err := s.redisClient.Publish(ctx, "orders:"+customerID, eventData).Err(); err != nil {
+			logctx.Error(ctx, "failed to publish event to customer channel", "event_id", eventID, "err", err)
+		}
+		s.recordRecentEvent(ctx, customerID, eventData)
+	}
+	if orderID != "" {
+		if err := s.redisClient.Publish(ctx, "orders:"+orderID, eventData).Err(); err != nil {
+			logctx.Error(ctx, "failed to publish event to order channel", "event_id", eventID, "err", err)
+		}
+	}
+}
+
+// recordRecentEvent appends the raw event payload to the bounded replay
+// buffer kept per customer so a reconnecting WebSocket client can catch up
+// on events it missed before subscribing live.
+func (s *OrderService) recordRecentEvent(ctx context.Context, customerID string, eventData []byte) {
+	key := fmt.Sprintf(recentEventsKeyFmt, customerID)
+
+	result, err := s.daprClient.GetState(ctx, stateStoreName, key, nil)
+	if err != nil {
+		logctx.Error(ctx, "failed to load recent events", "customer_id", customerID, "err", err)
+		return
+	}
+
+	var events []json.RawMessage
+	if len(result.Value) > 0 {
+		if err := json.Unmarshal(result.Value, &events); err != nil {
+			logctx.Error(ctx, "failed to parse recent events", "customer_id", customerID, "err", err)
+			events = nil
+		}
+	}
+
+	events = append(events, json.RawMessage(eventData))
+	if len(events) > maxRecentEvents {
+		events = events[len(events)-maxRecentEvents:]
+	}
+
+	data, err := json.Marshal(events)
+	if err != nil {
+		logctx.Error(ctx, "failed to marshal recent events", "customer_id", customerID, "err", err)
+		return
+	}
+
+	if err := s.daprClient.SaveState(ctx, stateStoreName, key, data, nil); err != nil {
+		logctx.Error(ctx, "failed to save recent events", "customer_id", customerID, "err", err)
+	}
+}
+
 func main() {
 	log.Println("Starting Order Service...")
 
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		shutdown, err := logctx.EnableOTLP(context.Background(), "order-service", endpoint)
+		if err != nil {
+			log.Printf("Failed to enable OTLP export: %v", err)
+		} else {
+			defer shutdown(context.Background())
+		}
+	}
+
 	// Create gRPC server
 	lis, err := net.Listen("tcp", port)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(logctx.UnaryServerInterceptor), grpc.ChainStreamInterceptor(logctx.StreamServerInterceptor))
 	orderService := NewOrderService()
 	pb.RegisterOrderServiceServer(s, orderService)
 
+	// Resume any sagas that were still in flight the last time this service
+	// stopped before accepting new traffic.
+	orderService.sagaCoordinator.ResumeInFlightSagas(context.Background())
+
+	// Serve the WebSocket order-status stream alongside gRPC.
+	wsSrv := newWSServer(orderService)
+	go func() {
+		log.Printf("WebSocket server listening on port %s", wsPort)
+		if err := http.ListenAndServe(wsPort, logctx.HTTPMiddleware(wsSrv.routes())); err != nil {
+			log.Fatalf("Failed to serve websocket: %v", err)
+		}
+	}()
+
 	log.Printf("Order Service listening on port %s", port)
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("Failed to serve: %v", err)
 	}
 }
-
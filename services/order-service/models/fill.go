@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// FillStatus is the lifecycle state of a single Fill.
+type FillStatus string
+
+const (
+	FillStatusRecorded  FillStatus = "RECORDED"
+	FillStatusCancelled FillStatus = "CANCELLED"
+)
+
+// Fill represents one partial (or full) execution against an order item,
+// e.g. a single shipment or inventory allocation. An order with items that
+// can be fulfilled incrementally accumulates one Fill per allocation rather
+// than being rewritten as a whole.
+type Fill struct {
+	FillID      string     `json:"fill_id"`
+	OrderID     string     `json:"order_id"`
+	ItemID      string     `json:"item_id"`
+	Quantity    int32      `json:"quantity"`
+	UnitPrice   float64    `json:"unit_price"`
+	Status      FillStatus `json:"status"`
+	Timestamp   time.Time  `json:"timestamp"`
+	ExternalRef string     `json:"external_ref,omitempty"`
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sumit760/event-driven-services-demo/pkg/logctx"
+	pb "github.com/sumit760/event-driven-services-demo/services/order-service/proto"
+)
+
+// SagaStepStatus is the lifecycle state of a single saga step.
+type SagaStepStatus string
+
+const (
+	SagaStepPending     SagaStepStatus = "PENDING"
+	SagaStepCompleted   SagaStepStatus = "COMPLETED"
+	SagaStepCompensated SagaStepStatus = "COMPENSATED"
+	SagaStepFailed      SagaStepStatus = "FAILED"
+)
+
+// SagaStatus is the overall lifecycle state of an order fulfillment saga.
+type SagaStatus string
+
+const (
+	SagaStatusRunning      SagaStatus = "RUNNING"
+	SagaStatusCompensating SagaStatus = "COMPENSATING"
+	SagaStatusCompleted    SagaStatus = "COMPLETED"
+	SagaStatusFailed       SagaStatus = "FAILED"
+)
+
+const (
+	sagaKeyPrefix   = "saga:"
+	sagaMaxAttempts = 5
+	sagaBaseBackoff = 200 * time.Millisecond
+	sagaMaxBackoff  = 5 * time.Second
+)
+
+// SagaStepState tracks the progress of one step of a saga.
+type SagaStepState struct {
+	Name      string         `json:"name"`
+	Status    SagaStepStatus `json:"status"`
+	Attempts  int            `json:"attempts"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// SagaState is the durable record of an order fulfillment saga, persisted in
+// the Dapr state store under the key "saga:{orderId}".
+type SagaState struct {
+	OrderID       string          `json:"order_id"`
+	CorrelationID string          `json:"correlation_id"`
+	Status        SagaStatus      `json:"status"`
+	Steps         []SagaStepState `json:"steps"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+func (s *SagaState) step(name string) *SagaStepState {
+	for i := range s.Steps {
+		if s.Steps[i].Name == name {
+			return &s.Steps[i]
+		}
+	}
+	return nil
+}
+
+// failedStep returns the name of the step that triggered compensation, so a
+// resumed COMPENSATING saga can be handed back to compensate() with the same
+// exclusion it would have had before restart. Returns "" if no step was ever
+// marked FAILED, in which case compensate treats every COMPLETED step as
+// eligible for rollback.
+func (s *SagaState) failedStep() string {
+	for i := range s.Steps {
+		if s.Steps[i].Status == SagaStepFailed {
+			return s.Steps[i].Name
+		}
+	}
+	return ""
+}
+
+// sagaStep pairs a forward action with its compensating action. Both must be
+// idempotent: they are deduplicated by {orderId, stepName} via the saga state
+// before they are ever invoked twice.
+type sagaStep struct {
+	name       string
+	invoke     func(ctx context.Context, order *pb.Order) error
+	compensate func(ctx context.Context, order *pb.Order) error
+}
+
+// SagaCoordinator drives order fulfillment as a sequence of local
+// transactions, compensating previously completed steps in reverse order if
+// any step ultimately fails.
+type SagaCoordinator struct {
+	orderSvc *OrderService
+	steps    []sagaStep
+}
+
+// NewSagaCoordinator creates a SagaCoordinator for the given OrderService.
+func NewSagaCoordinator(orderSvc *OrderService) *SagaCoordinator {
+	sc := &SagaCoordinator{orderSvc: orderSvc}
+	sc.steps = []sagaStep{
+		{name: "reserve-inventory", invoke: sc.reserveInventory, compensate: sc.releaseInventory},
+		{name: "charge-payment", invoke: sc.chargePayment, compensate: sc.refundPayment},
+		{name: "create-shipment", invoke: sc.createShipment, compensate: sc.cancelShipment},
+		{name: "notify-customer", invoke: sc.notifyCustomer, compensate: sc.sendCancellationNotice},
+	}
+	return sc
+}
+
+func sagaKey(orderID string) string {
+	return sagaKeyPrefix + orderID
+}
+
+// StartSaga persists the initial saga state and kicks off forward progress in
+// the background, so callers (e.g. CreateOrder) can return as soon as the
+// saga has started rather than waiting for it to complete.
+func (sc *SagaCoordinator) StartSaga(ctx context.Context, order *pb.Order) error {
+	ctx = logctx.WithFields(ctx, "order_id", order.OrderId)
+	now := time.Now()
+	state := &SagaState{
+		OrderID:       order.OrderId,
+		CorrelationID: uuid.New().String(),
+		Status:        SagaStatusRunning,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	for _, step := range sc.steps {
+		state.Steps = append(state.Steps, SagaStepState{
+			Name:      step.name,
+			Status:    SagaStepPending,
+			UpdatedAt: now,
+		})
+	}
+
+	if err := sc.saveState(ctx, state); err != nil {
+		return fmt.Errorf("failed to persist saga state for order %s: %w", order.OrderId, err)
+	}
+
+	sc.publishSagaEvent(ctx, "saga.started", state, order.CustomerId, "")
+
+	// Forward progress runs detached from the request context so it survives
+	// past the lifetime of the CreateOrder call that started it.
+	go sc.run(context.Background(), order, state)
+
+	return nil
+}
+
+// GetSaga loads the current saga state for an order.
+func (sc *SagaCoordinator) GetSaga(ctx context.Context, orderID string) (*SagaState, error) {
+	ctx = logctx.WithFields(ctx, "order_id", orderID)
+	result, err := sc.orderSvc.daprClient.GetState(ctx, stateStoreName, sagaKey(orderID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saga state for order %s: %w", orderID, err)
+	}
+	if len(result.Value) == 0 {
+		return nil, nil
+	}
+
+	var state SagaState
+	if err := json.Unmarshal(result.Value, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse saga state for order %s: %w", orderID, err)
+	}
+	return &state, nil
+}
+
+// ResumeInFlightSagas scans the state store for sagas that were still
+// RUNNING or COMPENSATING when the service last stopped and resumes forward
+// progress or compensation on each of them, respectively. It is called once
+// at startup.
+func (sc *SagaCoordinator) ResumeInFlightSagas(ctx context.Context) {
+	query := `{"filter":{"OR":[{"EQ":{"status":"RUNNING"}},{"EQ":{"status":"COMPENSATING"}}]}}`
+	resp, err := sc.orderSvc.daprClient.QueryStateAlpha1(ctx, stateStoreName, query, nil)
+	if err != nil {
+		logctx.Error(ctx, "failed to scan for in-flight sagas", "err", err)
+		return
+	}
+
+	for _, item := range resp.Results {
+		if !strings.HasPrefix(item.Key, sagaKeyPrefix) {
+			continue
+		}
+
+		var state SagaState
+		if err := json.Unmarshal(item.Value, &state); err != nil {
+			logctx.Error(ctx, "failed to parse saga state", "key", item.Key, "err", err)
+			continue
+		}
+		if state.Status != SagaStatusRunning && state.Status != SagaStatusCompensating {
+			continue
+		}
+
+		stateCtx := logctx.WithFields(ctx, "order_id", state.OrderID)
+		getResp, err := sc.orderSvc.GetOrder(stateCtx, &pb.GetOrderRequest{OrderId: state.OrderID})
+		if err != nil || !getResp.Success {
+			logctx.Error(stateCtx, "failed to load order for in-flight saga resume", "err", err)
+			continue
+		}
+
+		st := state
+		if st.Status == SagaStatusCompensating {
+			logctx.Info(stateCtx, "resuming in-flight saga compensation")
+			go sc.compensate(context.Background(), getResp.Order, &st, st.failedStep())
+			continue
+		}
+
+		logctx.Info(stateCtx, "resuming in-flight saga")
+		go sc.run(context.Background(), getResp.Order, &st)
+	}
+}
+
+func (sc *SagaCoordinator) run(ctx context.Context, order *pb.Order, state *SagaState) {
+	ctx = logctx.WithFields(ctx, "order_id", order.OrderId)
+	sc.updateOrderStatus(ctx, order.OrderId, pb.OrderStatus_ORDER_STATUS_PROCESSING)
+
+	for _, step := range sc.steps {
+		stepState := state.step(step.name)
+		if stepState.Status == SagaStepCompleted {
+			continue // already completed, dedup on resume
+		}
+
+		if err := sc.withRetry(ctx, step.name, func() error { return step.invoke(ctx, order) }); err != nil {
+			logctx.Error(ctx, "saga step failed after retries", "step", step.name, "err", err)
+			stepState.Status = SagaStepFailed
+			stepState.UpdatedAt = time.Now()
+			sc.compensate(ctx, order, state, step.name)
+			return
+		}
+
+		stepState.Status = SagaStepCompleted
+		stepState.Attempts++
+		stepState.UpdatedAt = time.Now()
+		state.UpdatedAt = time.Now()
+		if err := sc.saveState(ctx, state); err != nil {
+			logctx.Error(ctx, "failed to persist saga progress", "err", err)
+		}
+		sc.publishSagaEvent(ctx, "saga.step.completed", state, order.CustomerId, step.name)
+	}
+
+	state.Status = SagaStatusCompleted
+	state.UpdatedAt = time.Now()
+	if err := sc.saveState(ctx, state); err != nil {
+		logctx.Error(ctx, "failed to persist completed saga", "err", err)
+	}
+	sc.updateOrderStatus(ctx, order.OrderId, pb.OrderStatus_ORDER_STATUS_COMPLETED)
+	sc.publishSagaEvent(ctx, "saga.completed", state, order.CustomerId, "")
+}
+
+// compensate walks back through the steps in reverse order, invoking the
+// compensation of every step that had completed; failedStep itself is skipped.
+func (sc *SagaCoordinator) compensate(ctx context.Context, order *pb.Order, state *SagaState, failedStep string) {
+	state.Status = SagaStatusCompensating
+	state.UpdatedAt = time.Now()
+	_ = sc.saveState(ctx, state)
+	sc.publishSagaEvent(ctx, "saga.compensating", state, order.CustomerId, failedStep)
+
+	for i := len(sc.steps) - 1; i >= 0; i-- {
+		step := sc.steps[i]
+		if step.name == failedStep {
+			continue // the step that failed never completed, nothing to undo
+		}
+
+		stepState := state.step(step.name)
+		if stepState.Status != SagaStepCompleted {
+			continue
+		}
+
+		if err := sc.withRetry(ctx, step.name+"-compensate", func() error { return step.compensate(ctx, order) }); err != nil {
+			logctx.Error(ctx, "compensation failed", "step", step.name, "err", err)
+			continue
+		}
+
+		stepState.Status = SagaStepCompensated
+		stepState.UpdatedAt = time.Now()
+		_ = sc.saveState(ctx, state)
+	}
+
+	state.Status = SagaStatusFailed
+	state.UpdatedAt = time.Now()
+	_ = sc.saveState(ctx, state)
+	sc.updateOrderStatus(ctx, order.OrderId, pb.OrderStatus_ORDER_STATUS_FAILED)
+	sc.publishSagaEvent(ctx, "saga.failed", state, order.CustomerId, failedStep)
+}
+
+// withRetry retries fn with bounded exponential backoff.
+func (sc *SagaCoordinator) withRetry(ctx context.Context, label string, fn func() error) error {
+	var lastErr error
+	for attempt := 1; attempt <= sagaMaxAttempts; attempt++ {
+		if err := fn(); err == nil {
+			return nil
+		} else {
+			lastErr = err
+		}
+
+		if attempt == sagaMaxAttempts {
+			break
+		}
+
+		backoff := time.Duration(math.Pow(2, float64(attempt-1))) * sagaBaseBackoff
+		if backoff > sagaMaxBackoff {
+			backoff = sagaMaxBackoff
+		}
+		logctx.Warn(ctx, "retrying saga step", "step", label, "attempt", attempt+1, "max_attempts", sagaMaxAttempts, "backoff", backoff, "err", lastErr)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return fmt.Errorf("%s: %w", label, lastErr)
+}
+
+func (sc *SagaCoordinator) saveState(ctx context.Context, state *SagaState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return sc.orderSvc.daprClient.SaveState(ctx, stateStoreName, sagaKey(state.OrderID), data, nil)
+}
+
+// updateOrderStatus transitions the order's persisted status (via the same
+// UpdateOrder path the gRPC API uses) so GetOrder reflects saga progress and
+// subscribers receive the resulting order.updated event.
+func (sc *SagaCoordinator) updateOrderStatus(ctx context.Context, orderID string, status pb.OrderStatus) {
+	resp, err := sc.orderSvc.UpdateOrder(ctx, &pb.UpdateOrderRequest{OrderId: orderID, Status: status})
+	if err != nil || !resp.Success {
+		logctx.Error(ctx, "failed to update order status", "order_id", orderID, "status", status.String(), "err", err)
+	}
+}
+
+func (sc *SagaCoordinator) publishSagaEvent(ctx context.Context, eventType string, state *SagaState, customerID, stepName string) {
+	event := OrderEvent{
+		EventID:    uuid.New().String(),
+		EventType:  eventType,
+		OrderID:    state.OrderID,
+		CustomerID: customerID,
+		Status:     string(state.Status),
+		Step:       stepName,
+		Timestamp:  time.Now(),
+	}
+
+	topic := eventType
+	if err := sc.orderSvc.publishEvent(ctx, topic, event); err != nil {
+		logctx.Error(ctx, "failed to publish saga event", "event_type", eventType, "order_id", state.OrderID, "err", err)
+	}
+}
+
+// --- step implementations ---
+//
+// Each step is deduplicated by {orderId, stepName}: invoke is only ever
+// called for a step whose saga state is not already COMPLETED, and the
+// downstream services are expected to treat repeat calls with the same
+// dedup_key ("{orderId}:{method}", see invokeStep) as no-ops.
+
+func (sc *SagaCoordinator) reserveInventory(ctx context.Context, order *pb.Order) error {
+	return sc.invokeStep(ctx, "inventory-service", "reserve", order)
+}
+
+func (sc *SagaCoordinator) releaseInventory(ctx context.Context, order *pb.Order) error {
+	return sc.invokeStep(ctx, "inventory-service", "release", order)
+}
+
+func (sc *SagaCoordinator) chargePayment(ctx context.Context, order *pb.Order) error {
+	return sc.invokeStep(ctx, "payment-service", "charge", order)
+}
+
+func (sc *SagaCoordinator) refundPayment(ctx context.Context, order *pb.Order) error {
+	return sc.invokeStep(ctx, "payment-service", "refund", order)
+}
+
+func (sc *SagaCoordinator) createShipment(ctx context.Context, order *pb.Order) error {
+	return sc.invokeStep(ctx, "shipment-service", "create", order)
+}
+
+func (sc *SagaCoordinator) cancelShipment(ctx context.Context, order *pb.Order) error {
+	return sc.invokeStep(ctx, "shipment-service", "cancel", order)
+}
+
+func (sc *SagaCoordinator) notifyCustomer(ctx context.Context, order *pb.Order) error {
+	return sc.invokeStep(ctx, "notification-service", "notify", order)
+}
+
+func (sc *SagaCoordinator) sendCancellationNotice(ctx context.Context, order *pb.Order) error {
+	return sc.invokeStep(ctx, "notification-service", "notify-cancellation", order)
+}
+
+func (sc *SagaCoordinator) invokeStep(ctx context.Context, appID, method string, order *pb.Order) (err error) {
+	payload := map[string]interface{}{
+		"order_id":    order.OrderId,
+		"customer_id": order.CustomerId,
+		"dedup_key":   order.OrderId + ":" + method,
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	spanCtx, endSpan := logctx.StartSpan(ctx, "dapr.invoke "+appID+"/"+method)
+	defer func() { endSpan(&err) }()
+
+	_, err = sc.orderSvc.daprClient.InvokeMethod(logctx.OutgoingGRPCContext(spanCtx), appID, method, "POST", data)
+	if err != nil {
+		return fmt.Errorf("failed to invoke %s/%s: %w", appID, method, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/sumit760/event-driven-services-demo/pkg/cloudevents"
+	"github.com/sumit760/event-driven-services-demo/pkg/logctx"
+)
+
+const subscriptionsStateKey = "subscriptions"
+
+// Subscription is a third-party webhook registered to receive CloudEvents
+// envelopes over HTTP for a given resource filter (e.g. "order.created", or
+// "*" for every event).
+type Subscription struct {
+	ID       string `json:"id"`
+	URL      string `json:"url"`
+	Resource string `json:"resource"`
+}
+
+type subscribeRequest struct {
+	URL      string `json:"url"`
+	Resource string `json:"resource"`
+}
+
+type subscribeResponse struct {
+	ID      string `json:"id"`
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// handleSubscribe lets an external consumer register a webhook URL and
+// resource filter, and receive the same CloudEvents envelope published to
+// Kafka, without needing direct Kafka access.
+func (ws *wsServer) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req subscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.URL == "" || req.Resource == "" {
+		http.Error(w, "url and resource are required", http.StatusBadRequest)
+		return
+	}
+
+	sub := Subscription{ID: uuid.New().String(), URL: req.URL, Resource: req.Resource}
+	if err := ws.orderSvc.addSubscription(r.Context(), sub); err != nil {
+		logctx.Error(r.Context(), "failed to register subscription", "url", req.URL, "err", err)
+		http.Error(w, "failed to register subscription", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscribeResponse{ID: sub.ID, Success: true, Message: "Subscription registered"})
+}
+
+func (s *OrderService) addSubscription(ctx context.Context, sub Subscription) error {
+	subs, err := s.loadSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	subs = append(subs, sub)
+
+	data, err := json.Marshal(subs)
+	if err != nil {
+		return err
+	}
+	return s.daprClient.SaveState(ctx, stateStoreName, subscriptionsStateKey, data, nil)
+}
+
+func (s *OrderService) loadSubscriptions(ctx context.Context) ([]Subscription, error) {
+	result, err := s.daprClient.GetState(ctx, stateStoreName, subscriptionsStateKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subscriptions: %w", err)
+	}
+	if len(result.Value) == 0 {
+		return nil, nil
+	}
+
+	var subs []Subscription
+	if err := json.Unmarshal(result.Value, &subs); err != nil {
+		return nil, fmt.Errorf("failed to parse subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+// deliverToSubscriptions POSTs the CloudEvents envelope to every webhook
+// registered for the given topic (or for "*").
+func (s *OrderService) deliverToSubscriptions(ctx context.Context, topic string, event *cloudevents.Event) {
+	ctx = logctx.WithFields(ctx, "topic", topic)
+
+	subs, err := s.loadSubscriptions(ctx)
+	if err != nil {
+		logctx.Error(ctx, "failed to load subscriptions", "err", err)
+		return
+	}
+
+	data, err := event.Marshal()
+	if err != nil {
+		logctx.Error(ctx, "failed to marshal cloudevent for subscription delivery", "err", err)
+		return
+	}
+
+	for _, sub := range subs {
+		if sub.Resource != topic && !strings.EqualFold(sub.Resource, "*") {
+			continue
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(data))
+		if err != nil {
+			logctx.Error(ctx, "failed to build webhook request", "url", sub.URL, "err", err)
+			continue
+		}
+		req.Header.Set("Content-Type", cloudevents.ContentType)
+		logctx.SetOutgoingHeader(ctx, req)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			logctx.Error(ctx, "failed to deliver event to subscription", "url", sub.URL, "err", err)
+			continue
+		}
+		resp.Body.Close()
+	}
+}
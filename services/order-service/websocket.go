@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/sumit760/event-driven-services-demo/pkg/logctx"
+)
+
+const (
+	wsPort              = ":8081"
+	wsHeartbeatInterval = 30 * time.Second
+	wsAPIKeyHeader      = "X-API-Key"
+	recentEventsKeyFmt  = "events:%s"
+	maxRecentEvents     = 50
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// wsServer exposes a WebSocket transport that streams order events to
+// connected clients, fanned out over Redis pub/sub.
+type wsServer struct {
+	orderSvc *OrderService
+}
+
+func newWSServer(orderSvc *OrderService) *wsServer {
+	return &wsServer{orderSvc: orderSvc}
+}
+
+func (ws *wsServer) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws/orders/", ws.authMiddleware(ws.handleOrderStream))
+	mux.HandleFunc("/subscriptions", ws.handleSubscribe)
+	return mux
+}
+
+// handleOrderStream upgrades the connection and streams OrderEvents for the
+// requested customer until the client disconnects.
+func (ws *wsServer) handleOrderStream(w http.ResponseWriter, r *http.Request) {
+	customerID := strings.TrimPrefix(r.URL.Path, "/ws/orders/")
+	if customerID == "" {
+		http.Error(w, "customer id is required", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logctx.Error(r.Context(), "failed to upgrade websocket connection", "customer_id", customerID, "err", err)
+		return
+	}
+	defer conn.Close()
+
+	allowed := parseEventFilter(r.URL.Query().Get("event_type"))
+	lastEventID := r.URL.Query().Get("last_event_id")
+
+	ctx, cancel := context.WithCancel(logctx.WithFields(r.Context(), "customer_id", customerID))
+	defer cancel()
+
+	if lastEventID != "" {
+		for _, data := range ws.replayFrom(ctx, customerID, lastEventID) {
+			if !allowed(data) {
+				continue
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				logctx.Error(ctx, "failed to write replayed event to websocket", "err", err)
+				return
+			}
+		}
+	}
+
+	out := make(chan []byte, 16)
+
+	channel := fmt.Sprintf("orders:%s", customerID)
+	sub := ws.orderSvc.redisClient.Subscribe(ctx, channel)
+	defer sub.Close()
+
+	go func() {
+		for msg := range sub.Channel() {
+			out <- []byte(msg.Payload)
+		}
+	}()
+
+	go ws.readPump(conn, cancel)
+
+	ticker := time.NewTicker(wsHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-out:
+			if !allowed(data) {
+				continue
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				logctx.Error(ctx, "failed to write to websocket", "err", err)
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				logctx.Error(ctx, "failed to ping websocket", "err", err)
+				return
+			}
+		}
+	}
+}
+
+// readPump drains control frames from the client so disconnects are
+// detected promptly, cancelling the stream's context on any read error.
+func (ws *wsServer) readPump(conn *websocket.Conn, cancel context.CancelFunc) {
+	defer cancel()
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// replayFrom returns the events recorded after lastEventID from the state
+// store, in order, so a reconnecting client doesn't miss events published
+// while it was offline. The caller is expected to write these to the socket
+// before subscribing to live pub/sub, so replayed and live events aren't
+// interleaved out of order.
+func (ws *wsServer) replayFrom(ctx context.Context, customerID, lastEventID string) [][]byte {
+	result, err := ws.orderSvc.daprClient.GetState(ctx, stateStoreName, fmt.Sprintf(recentEventsKeyFmt, customerID), nil)
+	if err != nil || len(result.Value) == 0 {
+		return nil
+	}
+
+	var events []json.RawMessage
+	if err := json.Unmarshal(result.Value, &events); err != nil {
+		logctx.Error(ctx, "failed to parse recent events", "customer_id", customerID, "err", err)
+		return nil
+	}
+
+	var replayed [][]byte
+	replaying := false
+	for _, raw := range events {
+		var envelope struct {
+			EventID string `json:"event_id"`
+		}
+		if err := json.Unmarshal(raw, &envelope); err != nil {
+			continue
+		}
+
+		if replaying {
+			replayed = append(replayed, []byte(raw))
+		}
+		if envelope.EventID == lastEventID {
+			replaying = true
+		}
+	}
+	return replayed
+}
+
+// parseEventFilter builds a predicate from a comma-separated event_type
+// query parameter, e.g. "order.created,order.updated".
+func parseEventFilter(raw string) func(data []byte) bool {
+	if raw == "" {
+		return func([]byte) bool { return true }
+	}
+
+	allowed := make(map[string]bool)
+	for _, t := range strings.Split(raw, ",") {
+		allowed[strings.TrimSpace(t)] = true
+	}
+
+	return func(data []byte) bool {
+		var evt OrderEvent
+		if err := json.Unmarshal(data, &evt); err != nil {
+			return true
+		}
+		return allowed[evt.EventType]
+	}
+}
+
+// authMiddleware requires either a valid API key or a valid JWT bearer
+// token before allowing a client to open a stream.
+func (ws *wsServer) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if apiKey := r.Header.Get(wsAPIKeyHeader); apiKey != "" {
+			if !isValidAPIKey(apiKey) {
+				http.Error(w, "invalid api key", http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		if token == "" {
+			http.Error(w, "missing credentials: supply an API key or bearer token", http.StatusUnauthorized)
+			return
+		}
+		if _, err := parseJWT(token); err != nil {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func isValidAPIKey(key string) bool {
+	expected := os.Getenv("ORDER_SERVICE_API_KEY")
+	return expected != "" && key == expected
+}
+
+func parseJWT(tokenStr string) (*jwt.Token, error) {
+	secret := os.Getenv("ORDER_SERVICE_JWT_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("ORDER_SERVICE_JWT_SECRET is not configured")
+	}
+
+	return jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}), jwt.WithExpirationRequired())
+}